@@ -0,0 +1,9 @@
+// Package proto holds the protobuf/gRPC definition for the TorchControl
+// control-plane API and the code protoc generates from it.
+//
+// Run `go generate ./...` (with protoc, protoc-gen-go and
+// protoc-gen-go-grpc on PATH) to regenerate torch.pb.go and
+// torch_grpc.pb.go after editing torch.proto.
+package proto
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative torch.proto
@@ -0,0 +1,176 @@
+// Package capture runs a single go-torch profile-and-render job: fetch a
+// pprof profile, convert it to a flame graph, and write the svg (plus a
+// folded-stack sidecar for later diffing). It used to live inline in
+// main.go; pulling it out lets both the HTTP /pprof/ handler and the
+// gRPC control plane drive the same capture logic.
+package capture
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/uber/go-torch/pprof"
+	"github.com/uber/go-torch/renderer"
+	"github.com/uber/go-torch/torchlog"
+)
+
+// Options are the parameters for a go-torch capture, populated from
+// query params or gRPC request fields via gflags the same way the CLI
+// populates them from argv.
+type Options struct {
+	PProfOptions pprof.Options `group:"pprof Options"`
+	OutputOpts   OutputOptions `group:"Output Options"`
+}
+
+// OutputOptions control how the captured profile is rendered and saved.
+type OutputOptions struct {
+	File              string `short:"f" long:"file" default:"torch.svg" description:"Output file name (must be .svg)"`
+	Print             bool   `short:"p" long:"print" description:"Print the generated svg to stdout instead of writing to file"`
+	Raw               bool   `short:"r" long:"raw" description:"Print the raw call graph output to stdout instead of creating a flame graph; use with Brendan Gregg's flame graph perl script (see https://github.com/brendangregg/FlameGraph)"`
+	Title             string `long:"title" default:"Flame Graph" description:"Graph title to display in the output file"`
+	Width             int64  `long:"width" default:"1200" description:"Generated graph width"`
+	Hash              bool   `long:"hash" description:"Colors are keyed by function name hash"`
+	Colors            string `long:"colors" default:"" description:"set color palette. choices are: hot (default), mem, io, wakeup, chain, java, js, perl, red, green, blue, aqua, yellow, purple, orange"`
+	ConsistentPalette bool   `long:"cp" description:"Use consistent palette (palette.map)"`
+	Reverse           bool   `long:"reverse" description:"Generate stack-reversed flame graph"`
+	Inverted          bool   `long:"inverted" description:"icicle graph"`
+	WxKey             string `long:"wxkey" description:"wx notice key"`
+}
+
+// Run captures a profile and writes the rendered flame graph (and its
+// folded-stack sidecar, see RawSidecarPath) to allOpts.OutputOpts.File.
+func Run(allOpts *Options, remaining []string) error {
+	flameInput, err := Folded(allOpts, remaining)
+	if err != nil {
+		return err
+	}
+
+	opts := allOpts.OutputOpts
+	if opts.Raw {
+		torchlog.Print("Printing raw flamegraph input to stdout")
+		fmt.Printf("%s\n", flameInput)
+		return nil
+	}
+
+	flameGraphArgs := BuildFlameGraphArgs(opts)
+	flameGraph, err := renderer.GenerateFlameGraph(flameInput, flameGraphArgs...)
+	if err != nil {
+		return fmt.Errorf("could not generate flame graph: %v", err)
+	}
+
+	if opts.Print {
+		torchlog.Print("Printing svg to stdout")
+		fmt.Printf("%s\n", flameGraph)
+		return nil
+	}
+
+	torchlog.Printf("Writing svg to %v", opts.File)
+	if err := os.MkdirAll(filepath.Dir(opts.File), 0755); err != nil {
+		return fmt.Errorf("could not create output directory: %v", err)
+	}
+	if err := ioutil.WriteFile(opts.File, flameGraph, 0666); err != nil {
+		return fmt.Errorf("could not write output file: %v", err)
+	}
+	if err := ioutil.WriteFile(RawSidecarPath(opts.File), flameInput, 0666); err != nil {
+		torchlog.Print(err.Error())
+	}
+
+	return nil
+}
+
+// Folded runs pprof against remaining and returns the collapsed (folded)
+// stack input FlameGraph.pl consumes, without rendering or writing
+// anything. It's what /pprof/diff uses for a live baseline/target.
+func Folded(allOpts *Options, remaining []string) ([]byte, error) {
+	pprofRawOutput, err := pprof.GetRaw(allOpts.PProfOptions, remaining)
+	if err != nil {
+		return nil, fmt.Errorf("could not get raw output from pprof: %v", err)
+	}
+
+	profile, err := pprof.ParseRaw(pprofRawOutput)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse raw pprof output: %v", err)
+	}
+
+	sampleIndex := pprof.SelectSample(remaining, profile.SampleNames)
+	flameInput, err := renderer.ToFlameInput(profile, sampleIndex)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert stacks to flamegraph input: %v", err)
+	}
+	return flameInput, nil
+}
+
+// RawSidecarPath is where the folded stack input for an svg is kept, so
+// /pprof/diff can reuse a past capture by task ID instead of re-running
+// pprof against its original target.
+func RawSidecarPath(svgPath string) string {
+	return strings.TrimSuffix(svgPath, ".svg") + ".raw"
+}
+
+// Validate checks the options the way the original CLI flags did.
+func Validate(opts *Options) error {
+	file := opts.OutputOpts.File
+	if file != "" && !strings.HasSuffix(file, ".svg") {
+		return fmt.Errorf("output file must end in .svg")
+	}
+	if opts.PProfOptions.TimeSeconds < 1 {
+		return fmt.Errorf("seconds must be an integer greater than 0")
+	}
+
+	// extra FlameGraph options
+	if opts.OutputOpts.Title == "" {
+		return fmt.Errorf("flamegraph title should not be empty")
+	}
+	if opts.OutputOpts.Width <= 0 {
+		return fmt.Errorf("flamegraph default width is 1200 pixels")
+	}
+	if opts.OutputOpts.Colors != "" {
+		switch opts.OutputOpts.Colors {
+		case "hot", "mem", "io", "wakeup", "chain", "java", "js", "perl", "red", "green", "blue", "aqua", "yellow", "purple", "orange":
+			// valid
+		default:
+			return fmt.Errorf("unknown flamegraph colors %q", opts.OutputOpts.Colors)
+		}
+	}
+
+	return nil
+}
+
+// BuildFlameGraphArgs turns OutputOptions into FlameGraph.pl arguments.
+func BuildFlameGraphArgs(opts OutputOptions) []string {
+	var args []string
+
+	if opts.Title != "" {
+		args = append(args, "--title", opts.Title)
+	}
+
+	if opts.Width > 0 {
+		args = append(args, "--width", strconv.FormatInt(opts.Width, 10))
+	}
+
+	if opts.Colors != "" {
+		args = append(args, "--colors", opts.Colors)
+	}
+
+	if opts.Hash {
+		args = append(args, "--hash")
+	}
+
+	if opts.ConsistentPalette {
+		args = append(args, "--cp")
+	}
+
+	if opts.Reverse {
+		args = append(args, "--reverse")
+	}
+
+	if opts.Inverted {
+		args = append(args, "--inverted")
+	}
+
+	return args
+}
@@ -0,0 +1,72 @@
+// Package diffgraph renders differential flame graphs: two captured
+// profiles compared so stack width reflects the delta between them and
+// color encodes regression (more samples) vs. improvement (fewer).
+//
+// It uses the same trick as Brendan Gregg's difffolded.pl/flamegraph.pl
+// workflow: negate the baseline's collapsed-stack counts, concatenate
+// them with the target's, and let FlameGraph.pl's --negate mode turn
+// the sign of each stack's merged count into red/blue.
+package diffgraph
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/uber/go-torch/renderer"
+)
+
+// Negate returns folded stack input with every stack's trailing sample
+// count made negative, so it reads as "removed" when merged with a
+// positive-count input.
+func Negate(folded []byte) ([]byte, error) {
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(folded))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		idx := strings.LastIndex(line, " ")
+		if idx < 0 {
+			return nil, fmt.Errorf("diffgraph: malformed folded stack line %q", line)
+		}
+		count, err := strconv.ParseInt(line[idx+1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("diffgraph: malformed sample count in %q: %v", line, err)
+		}
+		fmt.Fprintf(&out, "%s %d\n", line[:idx], -count)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Merge concatenates a negated baseline with target, the input shape
+// FlameGraph.pl expects in --negate mode.
+func Merge(baseline, target []byte) ([]byte, error) {
+	negated, err := Negate(baseline)
+	if err != nil {
+		return nil, err
+	}
+	merged := make([]byte, 0, len(negated)+len(target))
+	merged = append(merged, negated...)
+	merged = append(merged, target...)
+	return merged, nil
+}
+
+// GenerateDiffFlameGraph renders a differential flame graph from two
+// already-collapsed (folded) stack inputs. args are passed through to
+// FlameGraph.pl the same way renderer.GenerateFlameGraph's callers do;
+// "--negate" is added automatically.
+func GenerateDiffFlameGraph(baseline, target []byte, args ...string) ([]byte, error) {
+	merged, err := Merge(baseline, target)
+	if err != nil {
+		return nil, fmt.Errorf("could not build differential flame graph input: %v", err)
+	}
+	args = append(args, "--negate")
+	return renderer.GenerateFlameGraph(merged, args...)
+}
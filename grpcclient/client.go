@@ -0,0 +1,61 @@
+// Package grpcclient is a thin wrapper around the generated TorchControl
+// client, so other services can drive profiling programmatically
+// (e.g. from a CI pipeline) without scraping go-torch-api's HTML/SVG
+// responses or hand-rolling the gRPC boilerplate.
+package grpcclient
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	pb "github.com/will0306/go-torch-api/api/proto"
+)
+
+// Client talks to a go-torch-api gRPC control plane.
+type Client struct {
+	conn *grpc.ClientConn
+	pb.TorchControlClient
+}
+
+// Dial connects to the TorchControl service at addr (host:port).
+func Dial(addr string) (*Client, error) {
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("could not dial %s: %v", addr, err)
+	}
+	return &Client{conn: conn, TorchControlClient: pb.NewTorchControlClient(conn)}, nil
+}
+
+// Close closes the underlying connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// WaitForTask starts a profile and blocks until it reaches a terminal
+// status, returning the final Task. This is the call a CI step wants:
+// block on completion instead of polling GetTask.
+func (c *Client) WaitForTask(ctx context.Context, req *pb.StartProfileRequest) (*pb.Task, error) {
+	started, err := c.StartProfile(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("could not start profile: %v", err)
+	}
+
+	stream, err := c.StreamTaskEvents(ctx, &pb.StreamTaskEventsRequest{Id: started.GetTaskId()})
+	if err != nil {
+		return nil, fmt.Errorf("could not stream task events: %v", err)
+	}
+	for {
+		if _, err := stream.Recv(); err != nil {
+			break
+		}
+	}
+
+	resp, err := c.GetTask(ctx, &pb.GetTaskRequest{Id: started.GetTaskId()})
+	if err != nil {
+		return nil, fmt.Errorf("could not get final task state: %v", err)
+	}
+	return resp.GetTask(), nil
+}
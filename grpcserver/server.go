@@ -0,0 +1,272 @@
+// Package grpcserver exposes the Service behind main.go's HTTP handlers
+// as the TorchControl gRPC service, so CI systems and other tooling can
+// drive profiling without scraping HTML/SVG responses.
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/will0306/go-torch-api/api/proto"
+	"github.com/will0306/go-torch-api/security"
+	"github.com/will0306/go-torch-api/service"
+	"github.com/will0306/go-torch-api/store"
+)
+
+// Server is a running TorchControl gRPC server.
+type Server struct {
+	grpcServer *grpc.Server
+	listener   net.Listener
+}
+
+// Listen binds port and returns a Server ready to Serve. svc backs
+// every RPC, so tasks started over gRPC show up in /tasks/ and vice
+// versa. guard applies the same auth, allowlist, per-target rate
+// limiting and concurrency cap as the HTTP /pprof/ routes — the control
+// plane is just another way to start a capture, not a way around those
+// checks. The per-source-IP rate limit of guard.Middleware is not
+// applied to RPCs.
+func Listen(port string, svc *service.Service, guard *security.Guard) (*Server, error) {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
+	if err != nil {
+		return nil, err
+	}
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(authUnaryInterceptor(guard)),
+		grpc.StreamInterceptor(authStreamInterceptor(guard)),
+	)
+	pb.RegisterTorchControlServer(grpcServer, &torchControlServer{svc: svc, guard: guard})
+	return &Server{grpcServer: grpcServer, listener: lis}, nil
+}
+
+// Serve blocks, accepting RPCs until the listener is closed.
+func (s *Server) Serve() error {
+	return s.grpcServer.Serve(s.listener)
+}
+
+// Stop gracefully stops the server.
+func (s *Server) Stop() {
+	s.grpcServer.GracefulStop()
+}
+
+// authUnaryInterceptor rejects a unary RPC before it reaches its handler
+// if it fails security.Guard.Authenticate, mirroring the authentication
+// step of guard.Middleware on the HTTP side (but not its per-IP rate
+// limit).
+func authUnaryInterceptor(guard *security.Guard) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !authenticateIncoming(ctx, guard, info.FullMethod) {
+			return nil, status.Error(codes.Unauthenticated, "unauthorized")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// authStreamInterceptor is authUnaryInterceptor for StreamTaskEvents,
+// the one streaming RPC.
+func authStreamInterceptor(guard *security.Guard) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !authenticateIncoming(ss.Context(), guard, info.FullMethod) {
+			return status.Error(codes.Unauthenticated, "unauthorized")
+		}
+		return handler(srv, ss)
+	}
+}
+
+// authenticateIncoming builds security.Creds from the incoming RPC's
+// metadata (the gRPC analogue of request headers) and full method name,
+// so the bearer/HMAC checks in package security don't need to know
+// anything about gRPC.
+func authenticateIncoming(ctx context.Context, guard *security.Guard, fullMethod string) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	creds := security.Creds{
+		Method: fullMethod,
+		Path:   fullMethod,
+		Header: func(key string) string {
+			vals := md.Get(key) // metadata.MD.Get lowercases key for us
+			if len(vals) == 0 {
+				return ""
+			}
+			return vals[0]
+		},
+	}
+	return guard.Authenticate(creds)
+}
+
+// guardErrToStatus turns a security.GuardError's HTTP status into the
+// nearest gRPC status code, so a rejected RPC gets a proper code instead
+// of the default codes.Unknown.
+func guardErrToStatus(err error) error {
+	ge, ok := err.(*security.GuardError)
+	if !ok {
+		return err
+	}
+	var code codes.Code
+	switch ge.Status {
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	default:
+		code = codes.Internal
+	}
+	return status.Error(code, ge.Msg)
+}
+
+type torchControlServer struct {
+	pb.UnimplementedTorchControlServer
+	svc   *service.Service
+	guard *security.Guard
+}
+
+func (s *torchControlServer) StartProfile(ctx context.Context, req *pb.StartProfileRequest) (*pb.StartProfileResponse, error) {
+	target := req.GetOptions().GetUrl()
+	if target == "" {
+		target = security.LocalTarget
+	}
+	release, err := s.guard.AdmitTarget(target)
+	if err != nil {
+		return nil, guardErrToStatus(err)
+	}
+
+	params := optionsToParams(req.GetOptions())
+	task, err := s.svc.StartProfile(params, req.GetOptions().GetUrl(), req.GetOptions().GetTitle(),
+		int(req.GetOptions().GetDurationSeconds()), req.GetNotifiers())
+	if err != nil {
+		release()
+		return nil, err
+	}
+	s.svc.ReleaseWhenDone(task.ID, release)
+	return &pb.StartProfileResponse{TaskId: task.ID}, nil
+}
+
+func (s *torchControlServer) GetTask(ctx context.Context, req *pb.GetTaskRequest) (*pb.GetTaskResponse, error) {
+	task, ok, err := s.svc.GetTask(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "task %q not found", req.GetId())
+	}
+	return &pb.GetTaskResponse{Task: taskToProto(task)}, nil
+}
+
+func (s *torchControlServer) ListTasks(ctx context.Context, req *pb.ListTasksRequest) (*pb.ListTasksResponse, error) {
+	list, err := s.svc.ListTasks(store.Filter{
+		Status: store.Status(req.GetStatus()),
+		From:   req.GetFrom(),
+		To:     req.GetTo(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.ListTasksResponse{}
+	for _, t := range list {
+		resp.Tasks = append(resp.Tasks, taskToProto(t))
+	}
+	return resp, nil
+}
+
+// StreamTaskEvents polls the shared TaskStore for status transitions on
+// the requested task and streams one TaskEvent per transition, exiting
+// once the task reaches a terminal status or the client disconnects.
+// There's no pub/sub under the store today, so this trades a little
+// latency (poll interval) for not needing one.
+func (s *torchControlServer) StreamTaskEvents(req *pb.StreamTaskEventsRequest, stream pb.TorchControl_StreamTaskEventsServer) error {
+	ctx := stream.Context()
+	var lastStatus store.Status
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		task, ok, err := s.svc.GetTask(req.GetId())
+		if err != nil {
+			return err
+		}
+		if ok && task.Status != lastStatus {
+			lastStatus = task.Status
+			if err := stream.Send(&pb.TaskEvent{TaskId: task.ID, Status: string(task.Status)}); err != nil {
+				return err
+			}
+			if task.Status == store.StatusDone || task.Status == store.StatusFailed {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *torchControlServer) CancelTask(ctx context.Context, req *pb.CancelTaskRequest) (*pb.CancelTaskResponse, error) {
+	_, err := s.svc.CancelTask(req.GetId())
+	if err != nil {
+		return &pb.CancelTaskResponse{Ok: false}, err
+	}
+	return &pb.CancelTaskResponse{Ok: true}, nil
+}
+
+// optionsToParams turns a proto Options message into the gflags-style
+// argv capture.Options already knows how to parse, so StartProfile
+// doesn't need a second options surface to validate.
+func optionsToParams(o *pb.Options) []string {
+	var params []string
+	if o.GetFile() != "" {
+		params = append(params, "-f", o.GetFile())
+	}
+	if o.GetUrl() != "" {
+		params = append(params, "-u", o.GetUrl())
+	}
+	if o.GetDurationSeconds() > 0 {
+		params = append(params, "-t", fmt.Sprintf("%d", o.GetDurationSeconds()))
+	}
+	if o.GetTitle() != "" {
+		params = append(params, "--title", o.GetTitle())
+	}
+	if o.GetWidth() > 0 {
+		params = append(params, "--width", fmt.Sprintf("%d", o.GetWidth()))
+	}
+	if o.GetColors() != "" {
+		params = append(params, "--colors", o.GetColors())
+	}
+	if o.GetHash() {
+		params = append(params, "--hash")
+	}
+	if o.GetConsistentPalette() {
+		params = append(params, "--cp")
+	}
+	if o.GetReverse() {
+		params = append(params, "--reverse")
+	}
+	if o.GetInverted() {
+		params = append(params, "--inverted")
+	}
+	return params
+}
+
+func taskToProto(t *store.Task) *pb.Task {
+	return &pb.Task{
+		Id:        t.ID,
+		Url:       t.URL,
+		Options:   t.Options,
+		StartTime: t.StartTime,
+		EndTime:   t.EndTime,
+		Status:    string(t.Status),
+		Output:    t.Output,
+		Error:     t.Error,
+	}
+}
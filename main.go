@@ -1,154 +1,40 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	cron "github.com/robfig/cron/v3"
-	"github.com/uber/go-torch/pprof"
-	"github.com/uber/go-torch/renderer"
 	"github.com/uber/go-torch/torchlog"
 
 	"github.com/go-ini/ini"
 	gflags "github.com/jessevdk/go-flags"
+
+	"github.com/will0306/go-torch-api/capture"
+	"github.com/will0306/go-torch-api/diffgraph"
+	"github.com/will0306/go-torch-api/grpcserver"
+	"github.com/will0306/go-torch-api/notifier"
+	"github.com/will0306/go-torch-api/scheduler"
+	"github.com/will0306/go-torch-api/security"
+	"github.com/will0306/go-torch-api/service"
+	"github.com/will0306/go-torch-api/store"
 )
 
-// options are the parameters for go-torch.
 var (
-	onProcessMap = map[string]int64{}
-	timeDiff     = 2
-	cfg          *ini.File
+	cfg   *ini.File
+	svc   *service.Service
+	sched *scheduler.Scheduler
+	guard *security.Guard
 )
 
-type options struct {
-	PProfOptions pprof.Options `group:"pprof Options"`
-	OutputOpts   outputOptions `group:"Output Options"`
-}
-
-type outputOptions struct {
-	File              string `short:"f" long:"file" default:"torch.svg" description:"Output file name (must be .svg)"`
-	Print             bool   `short:"p" long:"print" description:"Print the generated svg to stdout instead of writing to file"`
-	Raw               bool   `short:"r" long:"raw" description:"Print the raw call graph output to stdout instead of creating a flame graph; use with Brendan Gregg's flame graph perl script (see https://github.com/brendangregg/FlameGraph)"`
-	Title             string `long:"title" default:"Flame Graph" description:"Graph title to display in the output file"`
-	Width             int64  `long:"width" default:"1200" description:"Generated graph width"`
-	Hash              bool   `long:"hash" description:"Colors are keyed by function name hash"`
-	Colors            string `long:"colors" default:"" description:"set color palette. choices are: hot (default), mem, io, wakeup, chain, java, js, perl, red, green, blue, aqua, yellow, purple, orange"`
-	ConsistentPalette bool   `long:"cp" description:"Use consistent palette (palette.map)"`
-	Reverse           bool   `long:"reverse" description:"Generate stack-reversed flame graph"`
-	Inverted          bool   `long:"inverted" description:"icicle graph"`
-	WxKey             string `long:"wxkey" description:"wx notice key"`
-}
-
-func runWithOptions(allOpts *options, remaining []string) error {
-	pprofRawOutput, err := pprof.GetRaw(allOpts.PProfOptions, remaining)
-	if err != nil {
-		return fmt.Errorf("could not get raw output from pprof: %v", err)
-	}
-
-	profile, err := pprof.ParseRaw(pprofRawOutput)
-	if err != nil {
-		return fmt.Errorf("could not parse raw pprof output: %v", err)
-	}
-
-	sampleIndex := pprof.SelectSample(remaining, profile.SampleNames)
-	flameInput, err := renderer.ToFlameInput(profile, sampleIndex)
-	if err != nil {
-		return fmt.Errorf("could not convert stacks to flamegraph input: %v", err)
-	}
-
-	opts := allOpts.OutputOpts
-	if opts.Raw {
-		torchlog.Print("Printing raw flamegraph input to stdout")
-		fmt.Printf("%s\n", flameInput)
-		return nil
-	}
-
-	var flameGraphArgs = buildFlameGraphArgs(opts)
-	flameGraph, err := renderer.GenerateFlameGraph(flameInput, flameGraphArgs...)
-	if err != nil {
-		return fmt.Errorf("could not generate flame graph: %v", err)
-	}
-
-	if opts.Print {
-		torchlog.Print("Printing svg to stdout")
-		fmt.Printf("%s\n", flameGraph)
-		return nil
-	}
-
-	torchlog.Printf("Writing svg to %v", opts.File)
-	if err := ioutil.WriteFile(opts.File, flameGraph, 0666); err != nil {
-		return fmt.Errorf("could not write output file: %v", err)
-	}
-
-	return nil
-}
-
-func validateOptions(opts *options) error {
-	file := opts.OutputOpts.File
-	if file != "" && !strings.HasSuffix(file, ".svg") {
-		return fmt.Errorf("output file must end in .svg")
-	}
-	if opts.PProfOptions.TimeSeconds < 1 {
-		return fmt.Errorf("seconds must be an integer greater than 0")
-	}
-
-	// extra FlameGraph options
-	if opts.OutputOpts.Title == "" {
-		return fmt.Errorf("flamegraph title should not be empty")
-	}
-	if opts.OutputOpts.Width <= 0 {
-		return fmt.Errorf("flamegraph default width is 1200 pixels")
-	}
-	if opts.OutputOpts.Colors != "" {
-		switch opts.OutputOpts.Colors {
-		case "hot", "mem", "io", "wakeup", "chain", "java", "js", "perl", "red", "green", "blue", "aqua", "yellow", "purple", "orange":
-			// valid
-		default:
-			return fmt.Errorf("unknown flamegraph colors %q", opts.OutputOpts.Colors)
-		}
-	}
-
-	return nil
-}
-
-func buildFlameGraphArgs(opts outputOptions) []string {
-	var args []string
-
-	if opts.Title != "" {
-		args = append(args, "--title", opts.Title)
-	}
-
-	if opts.Width > 0 {
-		args = append(args, "--width", strconv.FormatInt(opts.Width, 10))
-	}
-
-	if opts.Colors != "" {
-		args = append(args, "--colors", opts.Colors)
-	}
-
-	if opts.Hash {
-		args = append(args, "--hash")
-	}
-
-	if opts.ConsistentPalette {
-		args = append(args, "--cp")
-	}
-
-	if opts.Reverse {
-		args = append(args, "--reverse")
-	}
-
-	if opts.Inverted {
-		args = append(args, "--inverted")
-	}
-
-	return args
-}
-
 func helloworld(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("hello world"))
 }
@@ -208,107 +94,416 @@ func getHandler(w http.ResponseWriter, r *http.Request) {
 	if q.Get("wxkey") != "" {
 		params = append(params, "--wxkey", q.Get("wxkey"))
 	}
+	notifyNames := splitNonEmpty(q.Get("notifiers"), ",")
 
-	opts := &options{}
+	defaultExpireSec := 30
+	if q.Get("t") != "" {
+		defaultExpireSec, _ = strconv.Atoi(q.Get("t"))
+	}
 
-	parser := gflags.NewParser(opts, gflags.Default|gflags.IgnoreUnknown)
-	parser.Usage = "[options] [binary] <profile source>"
-	remaining, err := parser.ParseArgs(params)
+	target := q.Get("u")
+	if target == "" {
+		target = security.LocalTarget
+	}
+	release, err := guard.AdmitTarget(target)
 	if err != nil {
-		w.Write([]byte(err.Error()))
+		writeGuardError(w, err)
+		return
+	}
+
+	task, err := svc.StartProfile(params, q.Get("u"), q.Get("title"), defaultExpireSec, notifyNames)
+	if err == service.ErrAlreadyRunning {
+		release()
+		http.Error(w, "last time wait for done", http.StatusConflict)
 		return
 	}
-	err = validateOptions(opts)
 	if err != nil {
-		w.Write([]byte(err.Error()))
+		release()
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	now := time.Now().Unix()
-	if val, ok := onProcessMap[q.Get("u")]; ok && val > now {
-		w.Write([]byte("last time wait for done"))
+
+	svc.ReleaseWhenDone(task.ID, release)
+	w.Write([]byte(task.ID))
+}
+
+// writeGuardError responds with the status a security.GuardError
+// carries, or 500 for any other error a Guard check returned.
+func writeGuardError(w http.ResponseWriter, err error) {
+	if ge, ok := err.(*security.GuardError); ok {
+		http.Error(w, ge.Msg, ge.Status)
 		return
 	}
-	defaultExpireSec := 30
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// splitNonEmpty splits s on sep, dropping empty fields, so
+// "notifiers=" and "notifiers=slack,,email" both behave sensibly.
+func splitNonEmpty(s, sep string) []string {
+	if s == "" {
+		return nil
+	}
+	var out []string
+	for _, part := range strings.Split(s, sep) {
+		if part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// diffHandler serves /pprof/diff?baseline=<taskid|url>&target=<taskid|url>,
+// rendering a differential flame graph where width reflects the delta
+// between the two and color encodes regression (red) vs. improvement
+// (blue). baseline/target may each be a previously captured task ID
+// (its stored folded output is reused) or a URL (profiled live).
+func diffHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	baselineRef := q.Get("baseline")
+	targetRef := q.Get("target")
+	if baselineRef == "" || targetRef == "" {
+		http.Error(w, "baseline and target query params are required", http.StatusBadRequest)
+		return
+	}
+
+	durationSec := 30
 	if q.Get("t") != "" {
-		defaultExpireSec, _ = strconv.Atoi(q.Get("t"))
+		durationSec, _ = strconv.Atoi(q.Get("t"))
 	}
-	expireTime := defaultExpireSec + timeDiff
-	onProcessMap[q.Get("u")] = now + int64(expireTime)
 
-	go func() {
-		err = runWithOptions(opts, remaining)
-		if err != nil {
-			torchlog.Print(err.Error())
-			//w.Write([]byte(err.Error()))
+	baselineInput, err := resolveFoldedInput(baselineRef, durationSec)
+	if err != nil {
+		if _, ok := err.(*security.GuardError); ok {
+			writeGuardError(w, err)
 			return
 		}
-		if opts.OutputOpts.WxKey != "" {
-			sendWxTextNotice(fmt.Sprintf("压力测试：%v, 已运行完毕，火焰图地址：%s/%v", q.Get("title"), cfg.Section("").Key("HOST"), opts.OutputOpts.File), opts.OutputOpts.WxKey)
+		http.Error(w, fmt.Sprintf("baseline: %v", err), http.StatusBadRequest)
+		return
+	}
+	targetInput, err := resolveFoldedInput(targetRef, durationSec)
+	if err != nil {
+		if _, ok := err.(*security.GuardError); ok {
+			writeGuardError(w, err)
+			return
 		}
-	}()
+		http.Error(w, fmt.Sprintf("target: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	opts := capture.OutputOptions{Title: "Differential Flame Graph", Width: 1200}
+	if q.Get("title") != "" {
+		opts.Title = q.Get("title")
+	}
+	if q.Get("width") != "" {
+		opts.Width, _ = strconv.ParseInt(q.Get("width"), 10, 64)
+	}
+	if q.Get("colors") != "" {
+		opts.Colors = q.Get("colors")
+	}
+
+	flameGraph, err := diffgraph.GenerateDiffFlameGraph(baselineInput, targetInput, capture.BuildFlameGraphArgs(opts)...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	outFile := fmt.Sprintf("svg/diff-%s.svg", store.NewID())
+	if q.Get("f") != "" {
+		outFile = fmt.Sprintf("svg/%s.svg", q.Get("f"))
+	}
+	if err := os.MkdirAll(filepath.Dir(outFile), 0755); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := ioutil.WriteFile(outFile, flameGraph, 0666); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write([]byte(outFile))
+}
+
+// resolveFoldedInput turns a baseline/target reference from /pprof/diff
+// into folded stack input: a task ID reuses that task's stored capture,
+// anything else is treated as a URL and profiled live.
+func resolveFoldedInput(ref string, durationSec int) ([]byte, error) {
+	if strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://") {
+		return captureFoldedFromURL(ref, durationSec)
+	}
+
+	task, ok, err := svc.GetTask(ref)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("no task or URL found for %q", ref)
+	}
+	if task.Status != store.StatusDone {
+		return nil, fmt.Errorf("task %q is not done (status=%s)", ref, task.Status)
+	}
+	return ioutil.ReadFile(capture.RawSidecarPath(task.Output))
+}
 
-	w.Write([]byte("ok"))
+func captureFoldedFromURL(url string, durationSec int) ([]byte, error) {
+	release, err := guard.AdmitTarget(url)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	params := []string{"-u", url, "-t", strconv.Itoa(durationSec)}
+	opts := &capture.Options{}
+	parser := gflags.NewParser(opts, gflags.Default|gflags.IgnoreUnknown)
+	remaining, err := parser.ParseArgs(params)
+	if err != nil {
+		return nil, err
+	}
+	return capture.Folded(opts, remaining)
 }
 
-func sendWxTextNotice(content, wxkey string) {
-	url := fmt.Sprintf("https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=%s", wxkey)
-	method := "POST"
-	textContent := fmt.Sprintf(`{
-        "msgtype": "text",
-        "text": {
-            "content": "%s"
-        }
-  }`, content)
-
-	payload := strings.NewReader(textContent)
-	/*
-	  payload := strings.NewReader(` {
-	        "msgtype": "text",
-	        "text": {
-	            "content": "hello world"
-	        }
-	   }`)
-	*/
-
-	client := &http.Client{}
-	req, err := http.NewRequest(method, url, payload)
+// tasksHandler serves GET /tasks/ (list, optionally filtered by status
+// and/or start time range) and GET /tasks/{id} (single task detail).
+func tasksHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/tasks/")
+	if id != "" {
+		taskDetailHandler(w, r, id)
+		return
+	}
+
+	q := r.URL.Query()
+	f := store.Filter{Status: store.Status(q.Get("status"))}
+	if v := q.Get("from"); v != "" {
+		f.From, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if v := q.Get("to"); v != "" {
+		f.To, _ = strconv.ParseInt(v, 10, 64)
+	}
 
+	list, err := svc.ListTasks(f)
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(list); err != nil {
 		torchlog.Print(err.Error())
+	}
+}
+
+func taskDetailHandler(w http.ResponseWriter, r *http.Request, id string) {
+	task, ok, err := svc.GetTask(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	req.Header.Add("Content-Type", "application/json")
+	if !ok {
+		http.Error(w, fmt.Sprintf("task %q not found", id), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(task); err != nil {
+		torchlog.Print(err.Error())
+	}
+}
 
-	res, err := client.Do(req)
+// reapStaleTasks marks tasks still "running" well past their expected
+// finish time as failed. A task only stays running this long if the
+// goroutine capturing it died without updating the store (e.g. the
+// process was killed), since Service.run always updates the task once
+// capture.Run returns.
+func reapStaleTasks() {
+	running, err := svc.ListTasks(store.Filter{Status: store.StatusRunning})
 	if err != nil {
 		torchlog.Print(err.Error())
 		return
 	}
-	defer res.Body.Close()
+	now := time.Now().Unix()
+	for _, t := range running {
+		if t.ExpireAt > now {
+			continue
+		}
+		torchlog.Printf("task %v is stale, marking failed", t.ID)
+		t.Status = store.StatusFailed
+		t.EndTime = now
+		t.Error = "task did not complete before its expected end time"
+		if err := svc.Tasks.Put(t); err != nil {
+			torchlog.Print(err.Error())
+		}
+	}
+}
 
-	body, err := ioutil.ReadAll(res.Body)
+// recoverTasks runs once at startup. Any task still marked "running" was
+// orphaned by the previous process exiting, and nothing in this process
+// will finish it. If its capture window hasn't elapsed yet it is left
+// "running" until the window ends and then marked failed; otherwise it
+// is marked failed immediately, so it doesn't linger as "running"
+// forever. The svg output is not checked in either case.
+func recoverTasks() {
+	running, err := svc.ListTasks(store.Filter{Status: store.StatusRunning})
 	if err != nil {
 		torchlog.Print(err.Error())
 		return
 	}
-	torchlog.Printf("send wx notice resp: %v", string(body))
+	now := time.Now().Unix()
+	for _, t := range running {
+		t := t
+		if t.ExpireAt <= now {
+			t.Status = store.StatusFailed
+			t.EndTime = now
+			t.Error = "task was still running when the server restarted"
+			if err := svc.Tasks.Put(t); err != nil {
+				torchlog.Print(err.Error())
+			}
+			continue
+		}
+		wait := time.Duration(t.ExpireAt-now) * time.Second
+		go func() {
+			time.Sleep(wait)
+			t.Status = store.StatusFailed
+			t.EndTime = time.Now().Unix()
+			t.Error = "task was still running when the server restarted"
+			if err := svc.Tasks.Put(t); err != nil {
+				torchlog.Print(err.Error())
+			}
+		}()
+	}
+}
+
+// newStore builds the Store (tasks and schedules) configured under
+// [store] in conf.ini. backend defaults to "memory"; "bolt" persists to
+// the BoltDB file at path.
+func newStore(cfg *ini.File) (store.Store, error) {
+	section := cfg.Section("store")
+	backend := section.Key("BACKEND").MustString("memory")
+
+	switch backend {
+	case "", "memory":
+		return store.NewMemoryStore(), nil
+	case "bolt":
+		path := section.Key("PATH").MustString("data/tasks.db")
+		return store.NewBoltStore(path)
+	default:
+		return nil, fmt.Errorf("unknown store backend %q", backend)
+	}
 }
 
-func getOnProcess(w http.ResponseWriter, r *http.Request) {
-	w.Write([]byte(fmt.Sprintf("%+v", onProcessMap)))
+// loadConfSchedules persists every [schedules.xxx] section in conf.ini
+// into the store, using "conf:xxx" as a stable ID so re-running this on
+// startup doesn't create duplicates of schedules already there. It only
+// writes to the store; sched.LoadAll (called after this) is what
+// actually registers cron entries, so a schedule only ever gets one.
+// Schedules created through POST /schedules/ don't come through here at
+// all; they're added (and registered) directly via sched.Add.
+func loadConfSchedules(cfg *ini.File, tasks store.Store) error {
+	for _, section := range cfg.Sections() {
+		name := section.Name()
+		if !strings.HasPrefix(name, "schedules.") {
+			continue
+		}
+		id := "conf:" + strings.TrimPrefix(name, "schedules.")
+		if _, ok, err := tasks.GetSchedule(id); err != nil {
+			return err
+		} else if ok {
+			continue
+		}
+
+		s := &store.Schedule{
+			ID:              id,
+			CronSpec:        section.Key("cron").String(),
+			URL:             section.Key("url").String(),
+			DurationSeconds: section.Key("duration_seconds").MustInt(30),
+			Title:           section.Key("title").String(),
+			FileTemplate:    section.Key("file_template").String(),
+			Notifiers:       splitNonEmpty(section.Key("notifiers").String(), ","),
+		}
+		if err := tasks.PutSchedule(s); err != nil {
+			return fmt.Errorf("could not persist schedule %q: %v", id, err)
+		}
+	}
+	return nil
 }
 
-func delExpireData() {
-	if len(onProcessMap) == 0 {
+// schedulesHandler serves GET /schedules/ (list), POST /schedules/
+// (create), DELETE /schedules/{id} (remove) and GET
+// /schedules/{id}/history (every task that schedule started, whatever
+// its status, oldest first).
+func schedulesHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/schedules/")
+	if rest != "" {
+		if strings.HasSuffix(rest, "/history") {
+			scheduleHistoryHandler(w, r, strings.TrimSuffix(rest, "/history"))
+			return
+		}
+		scheduleDetailHandler(w, r, rest)
 		return
 	}
-	now := time.Now().Unix()
-	for k, v := range onProcessMap {
-		if v > now {
-			continue
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := sched.List()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(list); err != nil {
+			torchlog.Print(err.Error())
 		}
-		torchlog.Printf("task %v is expired", k)
-		delete(onProcessMap, k)
+	case http.MethodPost:
+		var s store.Schedule
+		if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := sched.Add(&s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(s); err != nil {
+			torchlog.Print(err.Error())
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func scheduleDetailHandler(w http.ResponseWriter, r *http.Request, id string) {
+	switch r.Method {
+	case http.MethodGet:
+		s, ok, err := sched.Get(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.Error(w, fmt.Sprintf("schedule %q not found", id), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(s); err != nil {
+			torchlog.Print(err.Error())
+		}
+	case http.MethodDelete:
+		if err := sched.Remove(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func scheduleHistoryHandler(w http.ResponseWriter, r *http.Request, id string) {
+	list, err := svc.ListTasks(store.Filter{ScheduleID: id})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartTime < list[j].StartTime })
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		torchlog.Print(err.Error())
 	}
 }
 
@@ -326,19 +521,52 @@ func main() {
 	if err != nil {
 		return
 	}
+
+	tasks, err := newStore(cfg)
+	if err != nil {
+		return
+	}
+
+	notifiers, err := notifier.Load(cfg)
+	if err != nil {
+		return
+	}
+
+	svc = service.New(tasks, notifiers, cfg.Section("").Key("HOST").String())
+	recoverTasks()
+
+	guard, err = security.New(cfg)
+	if err != nil {
+		return
+	}
+
 	c := cron.New()
-	_, err = c.AddFunc("@every 1m", delExpireData)
+	_, err = c.AddFunc("@every 1m", reapStaleTasks)
 	if err != nil {
 		return
 	}
+
+	if err = loadConfSchedules(cfg, tasks); err != nil {
+		return
+	}
+	sched = scheduler.New(c, svc, guard)
+	if err = sched.LoadAll(); err != nil {
+		return
+	}
 	c.Start()
 
+	if err := startGRPCServer(cfg, svc, guard); err != nil {
+		return
+	}
+
 	http.Handle("/svg/", http.StripPrefix("/svg/", http.FileServer(http.Dir("svg"))))
 	http.Handle("/profile/", http.StripPrefix("/profile/", http.FileServer(http.Dir(fmt.Sprintf("%s", cfg.Section("").Key("PROFLE_PATH"))))))
 
 	http.HandleFunc("/", helloworld)
-	http.HandleFunc("/tasks/", getOnProcess)
-	http.HandleFunc("/pprof/", getHandler)
+	http.HandleFunc("/tasks/", guard.Middleware(tasksHandler))
+	http.HandleFunc("/pprof/", guard.Middleware(getHandler))
+	http.HandleFunc("/pprof/diff", guard.Middleware(diffHandler))
+	http.HandleFunc("/schedules/", guard.Middleware(schedulesHandler))
 	torchlog.Printf("list at : %v", cfg.Section("").Key("HTTP_PORT"))
 	err = http.ListenAndServe(fmt.Sprintf(":%v", cfg.Section("").Key("HTTP_PORT")), nil)
 	if err != nil {
@@ -346,3 +574,29 @@ func main() {
 	}
 
 }
+
+// startGRPCServer starts the gRPC control plane on the port configured
+// under [grpc] in conf.ini, if grpc.ENABLED is true. It shares svc (and
+// therefore the same TaskStore) with the HTTP handlers, and guard so a
+// StartProfile RPC is checked against the same allowlist, auth,
+// per-target rate limit and concurrency cap as a direct /pprof/ request.
+// The per-source-IP rate limit applies to HTTP requests only.
+func startGRPCServer(cfg *ini.File, svc *service.Service, guard *security.Guard) error {
+	section := cfg.Section("grpc")
+	if !section.Key("ENABLED").MustBool(false) {
+		return nil
+	}
+	port := section.Key("PORT").MustString("9001")
+
+	srv, err := grpcserver.Listen(port, svc, guard)
+	if err != nil {
+		return fmt.Errorf("could not start grpc server: %v", err)
+	}
+	go func() {
+		torchlog.Printf("grpc control plane listening at :%v", port)
+		if err := srv.Serve(); err != nil {
+			torchlog.Print(err.Error())
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,38 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DiscordNotifier posts to a Discord channel webhook URL.
+type DiscordNotifier struct {
+	WebhookURL string
+	DryRun     bool
+	Client     *http.Client
+}
+
+// NewDiscordNotifier returns a Notifier that posts to a Discord webhook.
+func NewDiscordNotifier(webhookURL string, dryRun bool) *DiscordNotifier {
+	return &DiscordNotifier{WebhookURL: webhookURL, DryRun: dryRun, Client: &http.Client{}}
+}
+
+func (n *DiscordNotifier) Name() string { return "discord" }
+
+func (n *DiscordNotifier) Notify(ctx context.Context, event TaskEvent) error {
+	if n.DryRun {
+		return nil
+	}
+
+	body := fmt.Sprintf(`{"content":%q}`, renderText(event))
+	return doWithRetry(ctx, defaultRetry, func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.WebhookURL, strings.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return n.Client.Do(req)
+	})
+}
@@ -0,0 +1,55 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"text/template"
+)
+
+// HTTPNotifier POSTs (or otherwise sends) a user-defined JSON body,
+// rendered from Template with event as its data, to URL. This is the
+// escape hatch for destinations that don't have a dedicated notifier.
+type HTTPNotifier struct {
+	URL      string
+	Method   string
+	Template *template.Template
+	DryRun   bool
+	Client   *http.Client
+}
+
+// NewHTTPNotifier parses tmpl as a text/template body and returns a
+// Notifier that renders it per-event and sends it to url via method
+// (defaults to POST).
+func NewHTTPNotifier(url, method, tmpl string, dryRun bool) (*HTTPNotifier, error) {
+	t, err := template.New("notifier").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse notifier template: %v", err)
+	}
+	if method == "" {
+		method = http.MethodPost
+	}
+	return &HTTPNotifier{URL: url, Method: method, Template: t, DryRun: dryRun, Client: &http.Client{}}, nil
+}
+
+func (n *HTTPNotifier) Name() string { return "http" }
+
+func (n *HTTPNotifier) Notify(ctx context.Context, event TaskEvent) error {
+	var buf bytes.Buffer
+	if err := n.Template.Execute(&buf, event); err != nil {
+		return fmt.Errorf("could not render notifier template: %v", err)
+	}
+	if n.DryRun {
+		return nil
+	}
+
+	return doWithRetry(ctx, defaultRetry, func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, n.Method, n.URL, bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return n.Client.Do(req)
+	})
+}
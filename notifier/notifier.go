@@ -0,0 +1,32 @@
+// Package notifier lets go-torch tell the outside world when a capture
+// finishes, through whichever channels an operator configures in
+// conf.ini, instead of the single hardcoded WeChat Work webhook.
+package notifier
+
+import (
+	"context"
+	"time"
+
+	"github.com/will0306/go-torch-api/store"
+)
+
+// TaskEvent describes a finished (or failed) capture. Notifiers render it
+// however fits their channel.
+type TaskEvent struct {
+	TaskID   string
+	Title    string
+	Status   store.Status
+	Duration time.Duration
+	SVGURL   string
+	Error    string
+}
+
+// Notifier delivers a TaskEvent to one destination.
+type Notifier interface {
+	// Name identifies the notifier's type (e.g. "slack") in log and error
+	// messages. The "notifiers" query param on /pprof/ matches the
+	// conf.ini section name a notifier was registered under (see Load),
+	// not Name.
+	Name() string
+	Notify(ctx context.Context, event TaskEvent) error
+}
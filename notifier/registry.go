@@ -0,0 +1,86 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-ini/ini"
+)
+
+const sectionPrefix = "notifiers."
+
+// Load builds one Notifier per [notifiers.xxx] section in cfg, keyed by
+// the part of the section name after the prefix (so "[notifiers.slack]"
+// registers as "slack"). That name is what callers match against the
+// /pprof/ "notifiers" query param.
+func Load(cfg *ini.File) (map[string]Notifier, error) {
+	notifiers := map[string]Notifier{}
+
+	for _, section := range cfg.Sections() {
+		if !strings.HasPrefix(section.Name(), sectionPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(section.Name(), sectionPrefix)
+		dryRun := section.Key("dry_run").MustBool(false)
+
+		n, err := build(section.Key("type").String(), section, dryRun)
+		if err != nil {
+			return nil, fmt.Errorf("notifiers.%s: %v", name, err)
+		}
+		notifiers[name] = n
+	}
+
+	return notifiers, nil
+}
+
+func build(kind string, section *ini.Section, dryRun bool) (Notifier, error) {
+	switch kind {
+	case "wechat":
+		return NewWeChatNotifier(section.Key("key").String(), dryRun), nil
+	case "slack":
+		return NewSlackNotifier(section.Key("webhook_url").String(), dryRun), nil
+	case "discord":
+		return NewDiscordNotifier(section.Key("webhook_url").String(), dryRun), nil
+	case "http":
+		return NewHTTPNotifier(
+			section.Key("url").String(),
+			section.Key("method").MustString(""),
+			section.Key("template").String(),
+			dryRun,
+		)
+	case "smtp":
+		to := strings.Split(section.Key("to").String(), ",")
+		for i := range to {
+			to[i] = strings.TrimSpace(to[i])
+		}
+		return NewSMTPNotifier(
+			section.Key("host").String(),
+			section.Key("port").MustString("25"),
+			section.Key("username").String(),
+			section.Key("password").String(),
+			section.Key("from").String(),
+			to,
+			dryRun,
+		), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier type %q", kind)
+	}
+}
+
+// Fanout sends event to each named notifier, ignoring names that aren't
+// registered. It returns one error for each notifier that failed after
+// retries, so the caller can log them without aborting the other sends.
+func Fanout(notifiers map[string]Notifier, names []string, event TaskEvent) []error {
+	var errs []error
+	for _, name := range names {
+		n, ok := notifiers[strings.TrimSpace(name)]
+		if !ok {
+			continue
+		}
+		if err := n.Notify(context.Background(), event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", n.Name(), err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,61 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// retryConfig controls the exponential backoff used by webhook-style
+// notifiers when the destination returns a 5xx status or the request
+// times out. 4xx responses are not retried since a retry would fail the
+// same way.
+type retryConfig struct {
+	maxAttempts int
+	baseDelay   time.Duration
+}
+
+var defaultRetry = retryConfig{maxAttempts: 3, baseDelay: 500 * time.Millisecond}
+
+// doWithRetry runs send, retrying with exponential backoff while send
+// returns a retryable error (a 5xx response or a timeout). It gives up
+// after cfg.maxAttempts and returns the last error.
+func doWithRetry(ctx context.Context, cfg retryConfig, send func() (*http.Response, error)) error {
+	var lastErr error
+	for attempt := 0; attempt < cfg.maxAttempts; attempt++ {
+		if attempt > 0 {
+			delay := cfg.baseDelay * time.Duration(1<<uint(attempt-1))
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		resp, err := send()
+		if err != nil {
+			lastErr = err
+			if !isTimeout(err) {
+				return err
+			}
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode < 500 {
+			if resp.StatusCode >= 300 {
+				return fmt.Errorf("notifier: unexpected status %v", resp.StatusCode)
+			}
+			return nil
+		}
+		lastErr = fmt.Errorf("notifier: server error %v", resp.StatusCode)
+	}
+	return lastErr
+}
+
+func isTimeout(err error) bool {
+	type timeouter interface{ Timeout() bool }
+	te, ok := err.(timeouter)
+	return ok && te.Timeout()
+}
@@ -0,0 +1,39 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SlackNotifier posts to a Slack incoming webhook URL.
+type SlackNotifier struct {
+	WebhookURL string
+	DryRun     bool
+	Client     *http.Client
+}
+
+// NewSlackNotifier returns a Notifier that posts to a Slack incoming
+// webhook.
+func NewSlackNotifier(webhookURL string, dryRun bool) *SlackNotifier {
+	return &SlackNotifier{WebhookURL: webhookURL, DryRun: dryRun, Client: &http.Client{}}
+}
+
+func (n *SlackNotifier) Name() string { return "slack" }
+
+func (n *SlackNotifier) Notify(ctx context.Context, event TaskEvent) error {
+	if n.DryRun {
+		return nil
+	}
+
+	body := fmt.Sprintf(`{"text":%q}`, renderText(event))
+	return doWithRetry(ctx, defaultRetry, func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.WebhookURL, strings.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return n.Client.Do(req)
+	})
+}
@@ -0,0 +1,71 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+// SMTPNotifier emails a plain-text summary through an SMTP relay. SMTP
+// has no notion of timeouts/5xx to retry on, so Notify is best-effort:
+// a send failure is returned as-is without the webhook retry logic.
+type SMTPNotifier struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	To       []string
+	DryRun   bool
+}
+
+// NewSMTPNotifier returns a Notifier that emails event summaries from
+// "from" to each address in "to" via the SMTP relay at host:port.
+func NewSMTPNotifier(host, port, username, password, from string, to []string, dryRun bool) *SMTPNotifier {
+	return &SMTPNotifier{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+		From:     from,
+		To:       to,
+		DryRun:   dryRun,
+	}
+}
+
+func (n *SMTPNotifier) Name() string { return "smtp" }
+
+func (n *SMTPNotifier) Notify(ctx context.Context, event TaskEvent) error {
+	// Title/Error come straight from the request that started the
+	// capture, and Title ends up in the Subject: header below; a CR/LF
+	// in either would let a caller smuggle extra headers (e.g. "Bcc:")
+	// into msg (CWE-93). Strip them before building anything.
+	event.Title = stripCRLF(event.Title)
+	event.Error = stripCRLF(event.Error)
+
+	subject := fmt.Sprintf("go-torch: %s %s", event.Title, event.Status)
+	body := renderText(event)
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n",
+		n.From, strings.Join(n.To, ", "), subject, body)
+
+	if n.DryRun {
+		return nil
+	}
+
+	addr := fmt.Sprintf("%s:%s", n.Host, n.Port)
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+	}
+	return smtp.SendMail(addr, auth, n.From, n.To, []byte(msg))
+}
+
+// stripCRLF removes carriage returns and newlines, so a string heading
+// into a raw SMTP header line can't terminate it early and inject
+// additional headers.
+func stripCRLF(s string) string {
+	s = strings.ReplaceAll(s, "\r", "")
+	s = strings.ReplaceAll(s, "\n", "")
+	return s
+}
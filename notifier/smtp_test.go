@@ -0,0 +1,11 @@
+package notifier
+
+import "testing"
+
+func TestStripCRLF(t *testing.T) {
+	in := "Pwned\r\nBcc:attacker@evil.com"
+	want := "PwnedBcc:attacker@evil.com"
+	if got := stripCRLF(in); got != want {
+		t.Fatalf("stripCRLF(%q) = %q, want %q", in, got, want)
+	}
+}
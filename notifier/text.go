@@ -0,0 +1,12 @@
+package notifier
+
+import "fmt"
+
+// renderText builds the plain-text summary shared by the chat-style
+// notifiers (WeChat Work, Slack, Discord).
+func renderText(event TaskEvent) string {
+	if event.Error != "" {
+		return fmt.Sprintf("[go-torch] %s failed after %s: %s", event.Title, event.Duration, event.Error)
+	}
+	return fmt.Sprintf("[go-torch] %s finished in %s, flame graph: %s", event.Title, event.Duration, event.SVGURL)
+}
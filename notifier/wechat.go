@@ -0,0 +1,44 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// WeChatNotifier posts a plain-text message to a WeChat Work group
+// webhook. This is the channel go-torch originally supported directly
+// via sendWxTextNotice.
+type WeChatNotifier struct {
+	Key    string
+	DryRun bool
+	Client *http.Client
+}
+
+// NewWeChatNotifier returns a Notifier that posts to the WeChat Work
+// webhook identified by key.
+func NewWeChatNotifier(key string, dryRun bool) *WeChatNotifier {
+	return &WeChatNotifier{Key: key, DryRun: dryRun, Client: &http.Client{}}
+}
+
+func (n *WeChatNotifier) Name() string { return "wechat" }
+
+func (n *WeChatNotifier) Notify(ctx context.Context, event TaskEvent) error {
+	content := renderText(event)
+	if n.DryRun {
+		return nil
+	}
+
+	url := fmt.Sprintf("https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=%s", n.Key)
+	body := fmt.Sprintf(`{"msgtype":"text","text":{"content":%q}}`, content)
+
+	return doWithRetry(ctx, defaultRetry, func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return n.Client.Do(req)
+	})
+}
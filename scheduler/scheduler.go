@@ -0,0 +1,177 @@
+// Package scheduler registers recurring profile captures on a cron
+// instance shared with the rest of main.go, so "profile this URL every
+// 15 minutes" is a Schedule in the store instead of an external cron job
+// shelling out to curl.
+package scheduler
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync"
+	"text/template"
+	"time"
+
+	cron "github.com/robfig/cron/v3"
+
+	"github.com/will0306/go-torch-api/security"
+	"github.com/will0306/go-torch-api/service"
+	"github.com/will0306/go-torch-api/store"
+)
+
+// Scheduler keeps the Schedules in a store.ScheduleStore registered as
+// entries on a *cron.Cron, and re-registers them on Add/Remove so
+// changes made through /schedules/ take effect without a restart.
+type Scheduler struct {
+	cron  *cron.Cron
+	svc   *service.Service
+	guard *security.Guard
+
+	mu      sync.Mutex
+	entries map[string]cron.EntryID // schedule ID -> cron entry
+}
+
+// New returns a Scheduler that adds jobs to c and runs captures through
+// svc, gated by guard the same as /pprof/ — a schedule's URL still has
+// to clear the allowlist, and each firing still spends the per-target
+// rate limit and concurrency slot, so a schedule can't be used as an
+// end-run around those protections. c is expected to already be running
+// (or Start()ed by the caller after LoadAll), matching how main.go owns
+// the *cron.Cron lifecycle.
+func New(c *cron.Cron, svc *service.Service, guard *security.Guard) *Scheduler {
+	return &Scheduler{cron: c, svc: svc, guard: guard, entries: make(map[string]cron.EntryID)}
+}
+
+// LoadAll registers every Schedule already in the store as a cron entry.
+// It's meant to be called once at startup, after recoverTasks, so a
+// restart picks back up the same recurring captures.
+func (s *Scheduler) LoadAll() error {
+	schedules, err := s.svc.Tasks.ListSchedules()
+	if err != nil {
+		return fmt.Errorf("could not list schedules: %v", err)
+	}
+	for _, sched := range schedules {
+		if err := s.register(sched); err != nil {
+			return fmt.Errorf("could not register schedule %q: %v", sched.ID, err)
+		}
+	}
+	return nil
+}
+
+// Add persists sched and registers it on the cron instance. If an ID is
+// not set, one is assigned. sched.URL is checked against the guard's
+// allowlist up front, so a schedule that would never be allowed to run
+// is rejected at creation instead of failing silently on every tick.
+func (s *Scheduler) Add(sched *store.Schedule) error {
+	if sched.ID == "" {
+		sched.ID = store.NewID()
+	}
+	if sched.CronSpec == "" {
+		return fmt.Errorf("cron_spec is required")
+	}
+	if sched.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	if !s.guard.AllowedTarget(sched.URL) {
+		return fmt.Errorf("target %q is not on the allowlist", sched.URL)
+	}
+	if err := s.svc.Tasks.PutSchedule(sched); err != nil {
+		return err
+	}
+	return s.register(sched)
+}
+
+// Remove unregisters the schedule's cron entry and deletes it from the
+// store. It does not touch tasks the schedule already produced.
+func (s *Scheduler) Remove(id string) error {
+	s.mu.Lock()
+	if entryID, ok := s.entries[id]; ok {
+		s.cron.Remove(entryID)
+		delete(s.entries, id)
+	}
+	s.mu.Unlock()
+	return s.svc.Tasks.DeleteSchedule(id)
+}
+
+// List returns every registered schedule.
+func (s *Scheduler) List() ([]*store.Schedule, error) {
+	return s.svc.Tasks.ListSchedules()
+}
+
+// Get looks up a single schedule by ID.
+func (s *Scheduler) Get(id string) (*store.Schedule, bool, error) {
+	return s.svc.Tasks.GetSchedule(id)
+}
+
+func (s *Scheduler) register(sched *store.Schedule) error {
+	entryID, err := s.cron.AddFunc(sched.CronSpec, func() { s.run(sched) })
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.entries[sched.ID] = entryID
+	s.mu.Unlock()
+	return nil
+}
+
+// run starts one capture for sched, gated by the same allowlist,
+// per-target rate limit and concurrency cap as a direct /pprof/ request
+// — a schedule firing is just another way to start a capture against a
+// target, not a bypass of those checks. Output goes under a dated
+// svg/YYYY-MM-DD/ directory so a schedule's history doesn't pile up as
+// thousands of files in svg/.
+func (s *Scheduler) run(sched *store.Schedule) {
+	release, err := s.guard.AdmitTarget(sched.URL)
+	if err != nil {
+		// A rate-limited or disallowed target isn't worth failing the
+		// scheduler over; it'll just try again next tick.
+		return
+	}
+
+	dateDir := time.Now().Format("2006-01-02")
+	file := renderFileTemplate(sched)
+	params := []string{
+		"-f", fmt.Sprintf("svg/%s/%s.svg", dateDir, file),
+		"-u", sched.URL,
+		"-t", strconv.Itoa(sched.DurationSeconds),
+	}
+	if sched.Title != "" {
+		params = append(params, "--title", sched.Title)
+	}
+	task, err := s.svc.StartScheduledProfile(params, sched.URL, sched.Title, sched.DurationSeconds, sched.Notifiers, sched.ID)
+	if err != nil {
+		// A running-capture collision or a bad capture isn't worth
+		// failing the scheduler over; it'll just try again next tick.
+		release()
+		return
+	}
+	s.svc.ReleaseWhenDone(task.ID, release)
+}
+
+// fileTemplateData is what a schedule's FileTemplate is executed
+// against, e.g. "{{.ScheduleID}}-{{.Timestamp}}".
+type fileTemplateData struct {
+	ScheduleID string
+	Timestamp  int64
+}
+
+// renderFileTemplate fills in sched.FileTemplate, falling back to the
+// schedule ID and a timestamp so every run gets a distinct filename even
+// without a template, and on any template error.
+func renderFileTemplate(sched *store.Schedule) string {
+	data := fileTemplateData{ScheduleID: sched.ID, Timestamp: time.Now().Unix()}
+	fallback := fmt.Sprintf("%s-%d", data.ScheduleID, data.Timestamp)
+	if sched.FileTemplate == "" {
+		return fallback
+	}
+
+	t, err := template.New("schedule-file").Parse(sched.FileTemplate)
+	if err != nil {
+		return fallback
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return fallback
+	}
+	return buf.String()
+}
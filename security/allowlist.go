@@ -0,0 +1,69 @@
+package security
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Allowlist restricts which target URLs /pprof/ will fetch from, so a
+// caller can't point this service at arbitrary internal addresses
+// (SSRF). Each pattern is "host-glob/path-glob" (path.Match syntax on
+// each half separately), e.g. "*.internal/debug/pprof/*" — host and
+// path are matched against the parsed URL's Hostname()/Path, not the
+// raw string, so a glob written for one host can't be satisfied by
+// stuffing extra path segments or userinfo into a different one.
+type Allowlist struct {
+	patterns []string
+}
+
+// NewAllowlist returns an Allowlist matching any of patterns. An empty
+// Allowlist allows every URL, matching this repo's convention of new
+// restrictions being opt-in (see [grpc] ENABLED, [store] BACKEND) —
+// operators are expected to set security.allowed_targets before exposing
+// this service beyond localhost.
+func NewAllowlist(patterns []string) *Allowlist {
+	return &Allowlist{patterns: patterns}
+}
+
+// Allowed reports whether target matches at least one configured
+// pattern. With no patterns configured, everything is allowed. target
+// must parse as an http(s) URL with no userinfo component; the userinfo
+// form ("http://allowed.internal@evil.com/") is rejected outright since
+// it's a classic way to smuggle a different real host past a reviewer
+// (or a naive string match) while Go's net/http dials evil.com.
+func (a *Allowlist) Allowed(target string) bool {
+	if len(a.patterns) == 0 {
+		return true
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.User != nil {
+		return false
+	}
+
+	for _, p := range a.patterns {
+		hostPattern, pathPattern := splitPattern(p)
+		hostOK, _ := path.Match(hostPattern, u.Hostname())
+		if !hostOK {
+			continue
+		}
+		if pathPattern == "" {
+			return true
+		}
+		if pathOK, _ := path.Match(pathPattern, u.Path); pathOK {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPattern splits "host-glob/path-glob" on the first slash; a
+// pattern with no slash matches any path on a matching host.
+func splitPattern(p string) (hostPattern, pathPattern string) {
+	i := strings.Index(p, "/")
+	if i < 0 {
+		return p, ""
+	}
+	return p[:i], p[i:]
+}
@@ -0,0 +1,39 @@
+package security
+
+// gate caps the number of pprof captures running at once, so a burst of
+// requests (even ones that pass the rate limiters) can't fork enough
+// pprof/FlameGraph.pl subprocesses to exhaust file descriptors or CPU.
+type gate struct {
+	slots chan struct{}
+}
+
+// newGate returns a gate allowing up to max concurrent acquisitions. max
+// <= 0 disables the cap (every acquire succeeds immediately).
+func newGate(max int) *gate {
+	if max <= 0 {
+		return nil
+	}
+	return &gate{slots: make(chan struct{}, max)}
+}
+
+// tryAcquire reserves a slot without blocking, returning false if the
+// gate is at capacity.
+func (g *gate) tryAcquire() bool {
+	if g == nil {
+		return true
+	}
+	select {
+	case g.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release frees a slot acquired by tryAcquire.
+func (g *gate) release() {
+	if g == nil {
+		return
+	}
+	<-g.slots
+}
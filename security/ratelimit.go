@@ -0,0 +1,76 @@
+package security
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// limiterIdleTTL and evictInterval bound how long a per-key limiter
+// sticks around after its last request, so a long-running process
+// talking to many distinct IPs/targets over its lifetime doesn't grow
+// keyedLimiter.limiters forever.
+const (
+	limiterIdleTTL = 10 * time.Minute
+	evictInterval  = 5 * time.Minute
+)
+
+// keyedLimiter hands out one token-bucket limiter per key (source IP or
+// target URL), created lazily so an operator doesn't need to know every
+// IP or target up front.
+type keyedLimiter struct {
+	limit rate.Limit
+	burst int
+
+	mu       sync.Mutex
+	limiters map[string]*limiterEntry
+}
+
+type limiterEntry struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// newKeyedLimiter returns a keyedLimiter allowing ratePerSec sustained
+// requests per key with bursts up to burst. ratePerSec <= 0 disables
+// limiting entirely (every key is always allowed), matching this repo's
+// convention of security features being opt-in via config.
+func newKeyedLimiter(ratePerSec float64, burst int) *keyedLimiter {
+	if ratePerSec <= 0 {
+		return nil
+	}
+	k := &keyedLimiter{limit: rate.Limit(ratePerSec), burst: burst, limiters: make(map[string]*limiterEntry)}
+	go k.evictIdleLoop()
+	return k
+}
+
+func (k *keyedLimiter) evictIdleLoop() {
+	ticker := time.NewTicker(evictInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		cutoff := time.Now().Add(-limiterIdleTTL)
+		k.mu.Lock()
+		for key, e := range k.limiters {
+			if e.lastUsed.Before(cutoff) {
+				delete(k.limiters, key)
+			}
+		}
+		k.mu.Unlock()
+	}
+}
+
+func (k *keyedLimiter) allow(key string) bool {
+	if k == nil {
+		return true
+	}
+	k.mu.Lock()
+	e, ok := k.limiters[key]
+	if !ok {
+		e = &limiterEntry{limiter: rate.NewLimiter(k.limit, k.burst)}
+		k.limiters[key] = e
+	}
+	e.lastUsed = time.Now()
+	k.mu.Unlock()
+	return e.limiter.Allow()
+}
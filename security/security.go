@@ -0,0 +1,137 @@
+// Package security guards every way this service can be told to fetch
+// and profile a URL — /pprof/, /pprof/diff, schedules, and the gRPC
+// control plane — against an SSRF-style target allowlist, request
+// authentication, per-IP and per-target rate limiting, and a global cap
+// on concurrent captures. Everything here is opt-in via the [security]
+// conf.ini section so existing deployments aren't broken by upgrading.
+package security
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/go-ini/ini"
+)
+
+// GuardError is returned by Guard methods that reject a request; Status
+// is the HTTP status the caller should respond with.
+type GuardError struct {
+	Status int
+	Msg    string
+}
+
+func (e *GuardError) Error() string { return e.Msg }
+
+// LocalTarget is the sentinel AdmitTarget/AllowedTarget expect in place
+// of a URL when a capture has no -u/--url target, i.e. it profiles this
+// process itself rather than fetching a remote pprof endpoint. It is
+// exempt from the allowlist (which only matches http(s) URLs) since
+// there's no SSRF risk in a capture that never dials out, but it still
+// spends the per-target rate limit and concurrency slot like any other
+// capture.
+const LocalTarget = "local"
+
+// Guard bundles every /pprof/ protection this package provides.
+type Guard struct {
+	auth        authenticator
+	allowlist   *Allowlist
+	perIP       *keyedLimiter
+	perTarget   *keyedLimiter
+	concurrency *gate
+}
+
+// New builds a Guard from the [security] section of cfg.
+func New(cfg *ini.File) (*Guard, error) {
+	section := cfg.Section("security")
+
+	auth, err := newAuthenticator(
+		section.Key("auth_mode").MustString("none"),
+		section.Key("auth_token").String(),
+		section.Key("hmac_secret").String(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Guard{
+		auth:        auth,
+		allowlist:   NewAllowlist(section.Key("allowed_targets").Strings(",")),
+		perIP:       newKeyedLimiter(section.Key("rate_limit_per_ip").MustFloat64(0), section.Key("rate_limit_burst_per_ip").MustInt(1)),
+		perTarget:   newKeyedLimiter(section.Key("rate_limit_per_target").MustFloat64(0), section.Key("rate_limit_burst_per_target").MustInt(1)),
+		concurrency: newGate(section.Key("max_concurrent_captures").MustInt(0)),
+	}, nil
+}
+
+// Middleware wraps next with authentication and per-source-IP rate
+// limiting. It runs before the target URL is even parsed, so a
+// misbehaving or unauthenticated caller never reaches the handler. Use
+// this on every HTTP route that can trigger a capture, directly
+// (/pprof/, /pprof/diff) or indirectly (/schedules/, which registers one
+// to run later).
+func (g *Guard) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		creds := Creds{Method: r.Method, Path: r.URL.Path, Header: r.Header.Get}
+		if !g.Authenticate(creds) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !g.perIP.allow(clientIP(r)) {
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Authenticate checks creds against the configured auth mode. It's
+// exported (unlike the rest of the Guard's internals) so transports
+// other than net/http — the gRPC interceptor — can reuse the same
+// bearer/HMAC logic by building Creds from their own request shape.
+func (g *Guard) Authenticate(creds Creds) bool {
+	return g.auth.authenticate(creds)
+}
+
+// AllowedTarget reports whether target passes the configured allowlist,
+// without touching rate limits or the concurrency gate. Use this to
+// validate a target once, up front, when it's persisted for later use
+// rather than fetched immediately — e.g. a schedule's URL at creation
+// time — so a disallowed target is rejected at the API boundary instead
+// of failing silently every time its cron job fires.
+func (g *Guard) AllowedTarget(target string) bool {
+	return target == LocalTarget || g.allowlist.Allowed(target)
+}
+
+// AdmitTarget checks target against the allowlist and per-target rate
+// limit, then reserves a slot in the global concurrency gate. On success
+// it returns a release func the caller must invoke (typically via
+// defer, or once the async capture it gated actually finishes) once the
+// capture finishes; on failure it returns a GuardError whose Status is
+// the HTTP status to respond with. target == LocalTarget skips the
+// allowlist (see its doc comment) but still counts against the rate
+// limit and concurrency gate.
+func (g *Guard) AdmitTarget(target string) (release func(), err error) {
+	if target != LocalTarget && !g.allowlist.Allowed(target) {
+		return nil, &GuardError{Status: http.StatusForbidden, Msg: fmt.Sprintf("target %q is not on the allowlist", target)}
+	}
+	if !g.perTarget.allow(target) {
+		return nil, &GuardError{Status: http.StatusTooManyRequests, Msg: fmt.Sprintf("rate limit exceeded for target %q", target)}
+	}
+	if !g.concurrency.tryAcquire() {
+		return nil, &GuardError{Status: http.StatusServiceUnavailable, Msg: "too many captures already in flight"}
+	}
+	return g.concurrency.release, nil
+}
+
+// clientIP returns the caller's address without the port, so a client
+// reusing a new ephemeral port each request still maps to the same rate
+// limit bucket. It doesn't honor X-Forwarded-For: behind a reverse
+// proxy, set security.rate_limit_per_ip based on the proxy's own address
+// instead, or terminate the proxy with real client IPs preserved.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
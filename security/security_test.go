@@ -0,0 +1,31 @@
+package security
+
+import "testing"
+
+// TestAdmitTargetAllowsLocalWithAllowlistConfigured reproduces the
+// no-u-query local capture: once an operator sets allowed_targets (as
+// conf.ini.example tells them to before exposing this service), every
+// capture with no URL still needs to go through AdmitTarget("local")
+// and must not be rejected by the allowlist, which only ever matches
+// http(s) URLs.
+func TestAdmitTargetAllowsLocalWithAllowlistConfigured(t *testing.T) {
+	g := &Guard{
+		auth:        noAuth{},
+		allowlist:   NewAllowlist([]string{"*.internal/debug/pprof/*"}),
+		concurrency: newGate(0),
+	}
+
+	release, err := g.AdmitTarget(LocalTarget)
+	if err != nil {
+		t.Fatalf("AdmitTarget(LocalTarget) = %v, want nil", err)
+	}
+	release()
+
+	if g.AllowedTarget(LocalTarget) != true {
+		t.Fatalf("AllowedTarget(LocalTarget) = false, want true")
+	}
+
+	if g.AllowedTarget("http://evil.com/") {
+		t.Fatalf("AllowedTarget(unlisted URL) = true, want false")
+	}
+}
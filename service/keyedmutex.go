@@ -0,0 +1,33 @@
+package service
+
+import "sync"
+
+// keyedMutex hands out one mutex per key, created lazily. The zero
+// value is ready to use.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// Lock blocks until the mutex for key is held.
+func (k *keyedMutex) Lock(key string) {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*sync.Mutex)
+	}
+	m, ok := k.locks[key]
+	if !ok {
+		m = &sync.Mutex{}
+		k.locks[key] = m
+	}
+	k.mu.Unlock()
+	m.Lock()
+}
+
+// Unlock releases the mutex for key.
+func (k *keyedMutex) Unlock(key string) {
+	k.mu.Lock()
+	m := k.locks[key]
+	k.mu.Unlock()
+	m.Unlock()
+}
@@ -0,0 +1,204 @@
+// Package service is the transport-agnostic core behind both the HTTP
+// /pprof/ handler and the gRPC control plane: starting a capture,
+// looking up its task, and listing/cancelling tasks, all against one
+// shared TaskStore and notifier registry.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	gflags "github.com/jessevdk/go-flags"
+	"github.com/uber/go-torch/torchlog"
+
+	"github.com/will0306/go-torch-api/capture"
+	"github.com/will0306/go-torch-api/notifier"
+	"github.com/will0306/go-torch-api/store"
+)
+
+// ErrAlreadyRunning is returned by StartProfile when a capture against
+// the same URL is already in flight.
+var ErrAlreadyRunning = errors.New("a capture for this url is already running")
+
+// TimeDiff pads the default expiry window so that a task isn't reaped as
+// stale the instant its requested duration elapses.
+const TimeDiff = 2
+
+// Service ties a Store (tasks plus the schedules that produce them) to
+// the notifier registry so both transports (HTTP and gRPC) start and
+// observe capture jobs the same way.
+type Service struct {
+	Tasks     store.Store
+	Notifiers map[string]notifier.Notifier
+	// Host is prefixed onto OutputOpts.File to build the svg URL passed
+	// to notifiers, e.g. "http://torch.internal:9000".
+	Host string
+
+	// urlLocks serializes the "is one already running for this URL"
+	// check against the Task insert in startProfile, one lock per URL,
+	// so two concurrent StartProfile calls for the same URL can't both
+	// pass the check before either has Put its Task.
+	urlLocks keyedMutex
+}
+
+// New returns a Service backed by tasks and notifiers.
+func New(tasks store.Store, notifiers map[string]notifier.Notifier, host string) *Service {
+	return &Service{Tasks: tasks, Notifiers: notifiers, Host: host}
+}
+
+// StartProfile parses params the same way the CLI parses argv, starts
+// the capture in the background, and returns its Task immediately with
+// status "running". notifyNames are fanned out to on completion.
+func (s *Service) StartProfile(params []string, url, title string, defaultExpireSec int, notifyNames []string) (*store.Task, error) {
+	return s.startProfile(params, url, title, defaultExpireSec, notifyNames, "")
+}
+
+// StartScheduledProfile is StartProfile for a capture triggered by the
+// scheduler rather than an HTTP/gRPC caller; the resulting Task carries
+// scheduleID so /schedules/{id}/history can find it later.
+func (s *Service) StartScheduledProfile(params []string, url, title string, defaultExpireSec int, notifyNames []string, scheduleID string) (*store.Task, error) {
+	return s.startProfile(params, url, title, defaultExpireSec, notifyNames, scheduleID)
+}
+
+func (s *Service) startProfile(params []string, url, title string, defaultExpireSec int, notifyNames []string, scheduleID string) (*store.Task, error) {
+	opts := &capture.Options{}
+	parser := gflags.NewParser(opts, gflags.Default|gflags.IgnoreUnknown)
+	parser.Usage = "[options] [binary] <profile source>"
+	remaining, err := parser.ParseArgs(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := capture.Validate(opts); err != nil {
+		return nil, err
+	}
+
+	// The running-check and the Put below must happen as one unit per
+	// URL, or two concurrent StartProfile calls for the same URL can
+	// both see "nothing running" before either has inserted its Task.
+	s.urlLocks.Lock(url)
+	defer s.urlLocks.Unlock(url)
+
+	running, err := s.Tasks.List(store.Filter{URL: url, Status: store.StatusRunning})
+	if err != nil {
+		return nil, err
+	}
+	if len(running) > 0 {
+		return nil, ErrAlreadyRunning
+	}
+
+	now := time.Now().Unix()
+	task := &store.Task{
+		ID:         store.NewID(),
+		URL:        url,
+		Options:    params,
+		StartTime:  now,
+		ExpireAt:   now + int64(defaultExpireSec+TimeDiff),
+		Status:     store.StatusRunning,
+		ScheduleID: scheduleID,
+	}
+	if err := s.Tasks.Put(task); err != nil {
+		return nil, err
+	}
+
+	go s.run(task, opts, remaining, title, notifyNames)
+	return task, nil
+}
+
+func (s *Service) run(task *store.Task, opts *capture.Options, remaining []string, title string, notifyNames []string) {
+	runErr := capture.Run(opts, remaining)
+	task.EndTime = time.Now().Unix()
+	duration := time.Duration(task.EndTime-task.StartTime) * time.Second
+
+	event := notifier.TaskEvent{TaskID: task.ID, Title: title, Duration: duration}
+
+	if runErr != nil {
+		task.Status = store.StatusFailed
+		task.Error = runErr.Error()
+		event.Status = task.Status
+		event.Error = runErr.Error()
+	} else {
+		task.Status = store.StatusDone
+		task.Output = opts.OutputOpts.File
+		event.Status = task.Status
+		event.SVGURL = fmt.Sprintf("%s/%s", s.Host, opts.OutputOpts.File)
+	}
+
+	if err := s.Tasks.Put(task); err != nil {
+		task.Error = task.Error + fmt.Sprintf(" (also failed to persist: %v)", err)
+		torchlog.Print(task.Error)
+	}
+
+	if runErr == nil && opts.OutputOpts.WxKey != "" {
+		// wxkey is a one-off webhook key passed with the request itself,
+		// unlike the registered notifiers in notifyNames, so it gets its
+		// own ad hoc WeChatNotifier instead of a registry lookup.
+		notifier.NewWeChatNotifier(opts.OutputOpts.WxKey, false).Notify(context.Background(), event)
+	}
+
+	if len(notifyNames) > 0 {
+		for _, err := range notifier.Fanout(s.Notifiers, notifyNames, event) {
+			torchlog.Print(err.Error())
+		}
+	}
+}
+
+// GetTask looks up a single task by ID.
+func (s *Service) GetTask(id string) (*store.Task, bool, error) {
+	return s.Tasks.Get(id)
+}
+
+// ListTasks returns tasks matching f.
+func (s *Service) ListTasks(f store.Filter) ([]*store.Task, error) {
+	return s.Tasks.List(f)
+}
+
+// ReleaseWhenDone polls taskID until it reaches a terminal status (or
+// the lookup errors or the task goes missing), then calls release. It's
+// for callers that gated a capture through security.Guard.AdmitTarget
+// but, unlike an HTTP handler wrapping a synchronous fetch, can't just
+// defer the release — the capture StartProfile/StartScheduledProfile
+// kicked off keeps running well after the caller (an HTTP handler, the
+// scheduler, a gRPC RPC) has already returned. There's no pub/sub under
+// the TaskStore to block on instead, so this trades a little latency
+// (the poll interval) for not needing one.
+func (s *Service) ReleaseWhenDone(taskID string, release func()) {
+	go func() {
+		defer release()
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for range ticker.C {
+			task, ok, err := s.Tasks.Get(taskID)
+			if err != nil || !ok || task.Status == store.StatusDone || task.Status == store.StatusFailed {
+				return
+			}
+		}
+	}()
+}
+
+// CancelTask marks a pending or running task as failed. It cannot
+// interrupt a capture already blocked inside pprof.GetRaw (go-torch's
+// pprof client has no cancellation hook), so a cancelled task's
+// goroutine may still run to completion and overwrite this status with
+// its real result; CancelTask is best read as "stop waiting on this".
+func (s *Service) CancelTask(id string) (*store.Task, error) {
+	task, ok, err := s.Tasks.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("task %q not found", id)
+	}
+	if task.Status != store.StatusRunning && task.Status != store.StatusPending {
+		return task, nil
+	}
+	task.Status = store.StatusFailed
+	task.Error = "cancelled"
+	task.EndTime = time.Now().Unix()
+	if err := s.Tasks.Put(task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
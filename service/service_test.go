@@ -0,0 +1,42 @@
+package service
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/will0306/go-torch-api/store"
+)
+
+// TestStartProfileRejectsConcurrentDuplicate fires StartProfile at the
+// same URL from many goroutines at once; exactly one should succeed and
+// the rest should see ErrAlreadyRunning, even though nothing has
+// finished running yet (capture.Run isn't invoked until the goroutine
+// Service.run starts, well after startProfile returns).
+func TestStartProfileRejectsConcurrentDuplicate(t *testing.T) {
+	svc := New(store.NewMemoryStore(), nil, "")
+
+	const n = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	successes := 0
+
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			_, err := svc.startProfile([]string{"-t", "5"}, "http://example.com", "", 1, nil, "")
+			if err == nil {
+				mu.Lock()
+				successes++
+				mu.Unlock()
+			} else if err != ErrAlreadyRunning {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if successes != 1 {
+		t.Fatalf("got %d concurrent successes, want 1", successes)
+	}
+}
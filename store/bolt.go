@@ -0,0 +1,146 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	tasksBucket     = []byte("tasks")
+	schedulesBucket = []byte("schedules")
+)
+
+// boltStore is a BoltDB-backed Store. Unlike memoryStore, tasks and
+// schedules survive a restart, which is what makes startup recovery of
+// "running" tasks meaningful.
+type boltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens (creating if necessary) a BoltDB file at path and
+// returns a Store backed by it.
+func NewBoltStore(path string) (Store, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not open bolt store at %q: %v", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(tasksBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(schedulesBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not init bolt store at %q: %v", path, err)
+	}
+	return &boltStore{db: db}, nil
+}
+
+func (b *boltStore) Put(t *Task) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("could not marshal task %q: %v", t.ID, err)
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).Put([]byte(t.ID), data)
+	})
+}
+
+func (b *boltStore) Get(id string) (*Task, bool, error) {
+	var t *Task
+	err := b.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(tasksBucket).Get([]byte(id))
+		if v == nil {
+			return nil
+		}
+		t = &Task{}
+		return json.Unmarshal(v, t)
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("could not read task %q: %v", id, err)
+	}
+	if t == nil {
+		return nil, false, nil
+	}
+	return t, true, nil
+}
+
+func (b *boltStore) List(f Filter) ([]*Task, error) {
+	var out []*Task
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).ForEach(func(_, v []byte) error {
+			t := &Task{}
+			if err := json.Unmarshal(v, t); err != nil {
+				return err
+			}
+			if f.match(t) {
+				out = append(out, t)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list tasks: %v", err)
+	}
+	return out, nil
+}
+
+func (b *boltStore) Close() error {
+	return b.db.Close()
+}
+
+func (b *boltStore) PutSchedule(s *Schedule) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("could not marshal schedule %q: %v", s.ID, err)
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(schedulesBucket).Put([]byte(s.ID), data)
+	})
+}
+
+func (b *boltStore) GetSchedule(id string) (*Schedule, bool, error) {
+	var s *Schedule
+	err := b.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(schedulesBucket).Get([]byte(id))
+		if v == nil {
+			return nil
+		}
+		s = &Schedule{}
+		return json.Unmarshal(v, s)
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("could not read schedule %q: %v", id, err)
+	}
+	if s == nil {
+		return nil, false, nil
+	}
+	return s, true, nil
+}
+
+func (b *boltStore) ListSchedules() ([]*Schedule, error) {
+	var out []*Schedule
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(schedulesBucket).ForEach(func(_, v []byte) error {
+			s := &Schedule{}
+			if err := json.Unmarshal(v, s); err != nil {
+				return err
+			}
+			out = append(out, s)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list schedules: %v", err)
+	}
+	return out, nil
+}
+
+func (b *boltStore) DeleteSchedule(id string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(schedulesBucket).Delete([]byte(id))
+	})
+}
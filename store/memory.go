@@ -0,0 +1,76 @@
+package store
+
+import "sync"
+
+// memoryStore is a sync.Map-backed Store. Tasks and schedules are lost
+// on restart, which is why recovery on startup treats any "running" task
+// it finds in a persistent store as belonging to a previous process.
+type memoryStore struct {
+	tasks     sync.Map // id -> *Task
+	schedules sync.Map // id -> *Schedule
+}
+
+// NewMemoryStore returns a Store that keeps all tasks and schedules in
+// memory.
+func NewMemoryStore() Store {
+	return &memoryStore{}
+}
+
+func (m *memoryStore) Put(t *Task) error {
+	cp := *t
+	m.tasks.Store(t.ID, &cp)
+	return nil
+}
+
+func (m *memoryStore) Get(id string) (*Task, bool, error) {
+	v, ok := m.tasks.Load(id)
+	if !ok {
+		return nil, false, nil
+	}
+	cp := *v.(*Task)
+	return &cp, true, nil
+}
+
+func (m *memoryStore) List(f Filter) ([]*Task, error) {
+	var out []*Task
+	m.tasks.Range(func(_, v interface{}) bool {
+		t := v.(*Task)
+		if f.match(t) {
+			cp := *t
+			out = append(out, &cp)
+		}
+		return true
+	})
+	return out, nil
+}
+
+func (m *memoryStore) Close() error {
+	return nil
+}
+
+func (m *memoryStore) PutSchedule(s *Schedule) error {
+	m.schedules.Store(s.ID, s)
+	return nil
+}
+
+func (m *memoryStore) GetSchedule(id string) (*Schedule, bool, error) {
+	v, ok := m.schedules.Load(id)
+	if !ok {
+		return nil, false, nil
+	}
+	return v.(*Schedule), true, nil
+}
+
+func (m *memoryStore) ListSchedules() ([]*Schedule, error) {
+	var out []*Schedule
+	m.schedules.Range(func(_, v interface{}) bool {
+		out = append(out, v.(*Schedule))
+		return true
+	})
+	return out, nil
+}
+
+func (m *memoryStore) DeleteSchedule(id string) error {
+	m.schedules.Delete(id)
+	return nil
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestMemoryStoreConcurrentAccess reproduces the mutate-then-Put pattern
+// service.Service.run uses on a task it owns: it mimics that by racing a
+// goroutine that repeatedly takes a task out of the store, mutates it
+// as if it were a private copy, and Puts it back, against a goroutine
+// that concurrently Lists the same task. Run with -race: a
+// memoryStore that hands back the same *Task pointer it stores would
+// flag a data race here.
+func TestMemoryStoreConcurrentAccess(t *testing.T) {
+	s := NewMemoryStore()
+	task := &Task{ID: "1", URL: "http://example.com", Status: StatusRunning}
+	if err := s.Put(task); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			got, ok, err := s.Get("1")
+			if err != nil || !ok {
+				t.Errorf("Get: ok=%v err=%v", ok, err)
+				return
+			}
+			got.Status = StatusDone
+			got.Output = "svg/out.svg"
+			if err := s.Put(got); err != nil {
+				t.Errorf("Put: %v", err)
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			if _, err := s.List(Filter{URL: "http://example.com"}); err != nil {
+				t.Errorf("List: %v", err)
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+}
@@ -0,0 +1,114 @@
+// Package store tracks pprof capture jobs so handlers, the expiry cron and
+// the background goroutine that actually runs go-torch no longer share a
+// bare map with no synchronization.
+package store
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Status is the lifecycle state of a Task.
+type Status string
+
+// Task lifecycle states.
+const (
+	StatusPending Status = "pending"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+// Task is a single pprof capture job, from request to finished flame graph.
+type Task struct {
+	ID         string   `json:"id"`
+	URL        string   `json:"url"`
+	Options    []string `json:"options"`
+	StartTime  int64    `json:"start_time"`
+	ExpireAt   int64    `json:"expire_at,omitempty"`
+	EndTime    int64    `json:"end_time,omitempty"`
+	Status     Status   `json:"status"`
+	Output     string   `json:"output,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	ScheduleID string   `json:"schedule_id,omitempty"`
+}
+
+// Filter narrows List results. Zero values are treated as "don't filter".
+type Filter struct {
+	URL        string
+	Status     Status
+	From       int64
+	To         int64
+	ScheduleID string
+}
+
+func (f Filter) match(t *Task) bool {
+	if f.URL != "" && t.URL != f.URL {
+		return false
+	}
+	if f.Status != "" && t.Status != f.Status {
+		return false
+	}
+	if f.From > 0 && t.StartTime < f.From {
+		return false
+	}
+	if f.To > 0 && t.StartTime > f.To {
+		return false
+	}
+	if f.ScheduleID != "" && t.ScheduleID != f.ScheduleID {
+		return false
+	}
+	return true
+}
+
+// TaskStore persists Task state. Implementations must be safe for
+// concurrent use by multiple goroutines.
+type TaskStore interface {
+	// Put creates or overwrites the task with the given ID.
+	Put(t *Task) error
+	// Get returns the task with the given ID, or ok=false if it doesn't exist.
+	Get(id string) (t *Task, ok bool, err error)
+	// List returns every task matching f, in no particular order.
+	List(f Filter) ([]*Task, error)
+	// Close releases any resources held by the store.
+	Close() error
+}
+
+// Schedule is a recurring profile job: run URL for DurationSeconds on
+// CronSpec, rendering FileTemplate into a dated svg/YYYY-MM-DD/
+// directory and notifying Notifiers when it finishes.
+type Schedule struct {
+	ID              string   `json:"id"`
+	CronSpec        string   `json:"cron_spec"`
+	URL             string   `json:"url"`
+	DurationSeconds int      `json:"duration_seconds"`
+	Title           string   `json:"title"`
+	FileTemplate    string   `json:"file_template"`
+	Notifiers       []string `json:"notifiers,omitempty"`
+}
+
+// ScheduleStore persists Schedules. Implementations must be safe for
+// concurrent use by multiple goroutines.
+type ScheduleStore interface {
+	PutSchedule(s *Schedule) error
+	GetSchedule(id string) (s *Schedule, ok bool, err error)
+	ListSchedules() ([]*Schedule, error)
+	DeleteSchedule(id string) error
+}
+
+// Store is the full persistence surface go-torch-api needs: tasks and
+// the schedules that produce them, backed by the same memory/BoltDB
+// implementation.
+type Store interface {
+	TaskStore
+	ScheduleStore
+}
+
+var idCounter int64
+
+// NewID returns a task ID that is unique within this process.
+func NewID() string {
+	n := atomic.AddInt64(&idCounter, 1)
+	return fmt.Sprintf("%d-%d", time.Now().UnixNano(), n)
+}